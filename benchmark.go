@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/brandur/modulir"
+	"github.com/spf13/cobra"
+)
+
+//////////////////////////////////////////////////////////////////////////////
+//
+//
+//
+// Benchmark command
+//
+//
+//
+//////////////////////////////////////////////////////////////////////////////
+
+// benchmarkOptions holds the flags accepted by the `benchmark` subcommand.
+type benchmarkOptions struct {
+	count        int
+	cpuProfile   string
+	memProfile   string
+	traceProfile string
+}
+
+func newBenchmarkCmd(flags *buildFlags) *cobra.Command {
+	opts := &benchmarkOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "benchmark",
+		Short: "Run the build repeatedly and report timings",
+		Long: strings.TrimSpace(`
+Runs the build N times in a row, forcing a full rebuild on every
+iteration, and prints per-iteration timings along with a final summary
+(min/max/mean/stddev, total jobs run, and cache hit ratio). Useful for
+measuring the effect of a change to the build function or to the
+modulir job pool. Modeled after Hugo's own benchmark command.`),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runBenchmark(flags, opts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error running benchmark: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().IntVarP(&opts.count, "count", "n", 13, "Number of times to run the build")
+	cmd.Flags().StringVar(&opts.cpuProfile, "cpuprofile", "", "Write a CPU profile to this file")
+	cmd.Flags().StringVar(&opts.memProfile, "memprofile", "", "Write a memory profile to this file")
+	cmd.Flags().StringVar(&opts.traceProfile, "traceprofile", "", "Write an execution trace to this file")
+
+	return cmd
+}
+
+// runBenchmark runs the build function opts.count times, forcing a full
+// rebuild on every iteration so that timings are comparable, and prints a
+// summary at the end. It returns an error if any iteration's build
+// produces errors.
+func runBenchmark(flags *buildFlags, opts *benchmarkOptions) error {
+	if opts.count <= 0 {
+		return fmt.Errorf("--count must be at least 1, got %d", opts.count)
+	}
+
+	if opts.cpuProfile != "" {
+		f, err := os.Create(opts.cpuProfile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return err
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	if opts.traceProfile != "" {
+		f, err := os.Create(opts.traceProfile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := trace.Start(f); err != nil {
+			return err
+		}
+		defer trace.Stop()
+	}
+
+	forceAll = true
+	defer func() { forceAll = false }()
+
+	durations := make([]time.Duration, 0, opts.count)
+	var totalJobs, totalRun, totalCached int64
+
+	config, err := flags.modulirConfig()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < opts.count; i++ {
+		atomic.StoreInt64(&jobsTotal, 0)
+		atomic.StoreInt64(&jobsRun, 0)
+		atomic.StoreInt64(&jobsCached, 0)
+
+		start := time.Now()
+		errs := modulir.Build(config, build)
+		elapsed := time.Since(start)
+
+		if len(errs) > 0 {
+			return fmt.Errorf("iteration %d produced %d error(s): %v", i+1, len(errs), errs)
+		}
+
+		durations = append(durations, elapsed)
+		totalJobs += atomic.LoadInt64(&jobsTotal)
+		totalRun += atomic.LoadInt64(&jobsRun)
+		totalCached += atomic.LoadInt64(&jobsCached)
+
+		fmt.Printf("iteration %d: %v\n", i+1, elapsed)
+	}
+
+	if opts.memProfile != "" {
+		f, err := os.Create(opts.memProfile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			return err
+		}
+	}
+
+	printSummary(durations, totalJobs, totalRun, totalCached)
+
+	return nil
+}
+
+// printSummary prints min/max/mean/stddev timings along with the total
+// number of jobs run across all iterations and the resulting cache hit
+// ratio.
+//
+// totalCached (how many jobs change detection would have skipped absent
+// forcing) is tracked separately from totalRun (how many jobs actually
+// ran), since runBenchmark forces every job to run via forceAll so that
+// timings are comparable — under that forcing, totalRun always equals
+// totalJobs, so the cache hit ratio has to come from totalCached
+// instead.
+func printSummary(durations []time.Duration, totalJobs, totalRun, totalCached int64) {
+	min, max, mean, stddev := summaryStats(durations)
+
+	var cacheHitRatio float64
+	if totalJobs > 0 {
+		cacheHitRatio = float64(totalCached) / float64(totalJobs)
+	}
+
+	fmt.Printf("\nsummary over %d iteration(s):\n", len(durations))
+	fmt.Printf("  min:    %v\n", min)
+	fmt.Printf("  max:    %v\n", max)
+	fmt.Printf("  mean:   %v\n", mean)
+	fmt.Printf("  stddev: %v\n", stddev)
+	fmt.Printf("  total jobs run: %d/%d (cache hit ratio: %.1f%%)\n",
+		totalRun, totalJobs, cacheHitRatio*100)
+}
+
+// summaryStats computes the min, max, mean, and standard deviation of
+// durations, which must be non-empty.
+func summaryStats(durations []time.Duration) (min, max, mean, stddev time.Duration) {
+	min, max = durations[0], durations[0]
+	var sum time.Duration
+	for _, d := range durations {
+		if d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+		sum += d
+	}
+	mean = sum / time.Duration(len(durations))
+
+	var variance float64
+	for _, d := range durations {
+		diff := float64(d - mean)
+		variance += diff * diff
+	}
+	variance /= float64(len(durations))
+	stddev = time.Duration(math.Sqrt(variance))
+
+	return min, max, mean, stddev
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummaryStats(t *testing.T) {
+	durations := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		300 * time.Millisecond,
+	}
+
+	min, max, mean, stddev := summaryStats(durations)
+
+	if min != 100*time.Millisecond {
+		t.Errorf("min = %v, want %v", min, 100*time.Millisecond)
+	}
+	if max != 300*time.Millisecond {
+		t.Errorf("max = %v, want %v", max, 300*time.Millisecond)
+	}
+	if mean != 200*time.Millisecond {
+		t.Errorf("mean = %v, want %v", mean, 200*time.Millisecond)
+	}
+
+	// Population stddev of [100, 200, 300]ms is ~81.65ms; allow some
+	// slack for the float64 -> time.Duration round trip.
+	wantStddev := 81650 * time.Microsecond
+	diff := stddev - wantStddev
+	if diff < -time.Millisecond || diff > time.Millisecond {
+		t.Errorf("stddev = %v, want approximately %v", stddev, wantStddev)
+	}
+}
+
+func TestSummaryStatsSingleValue(t *testing.T) {
+	min, max, mean, stddev := summaryStats([]time.Duration{50 * time.Millisecond})
+
+	if min != 50*time.Millisecond || max != 50*time.Millisecond || mean != 50*time.Millisecond {
+		t.Errorf("min/max/mean = %v/%v/%v, want all 50ms", min, max, mean)
+	}
+	if stddev != 0 {
+		t.Errorf("stddev = %v, want 0", stddev)
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"sync/atomic"
+	"time"
+
 	"github.com/brandur/modulir"
-	"github.com/brandur/modulir/modules/mfile"
 )
 
 //////////////////////////////////////////////////////////////////////////////
@@ -15,6 +17,57 @@ import (
 //
 //////////////////////////////////////////////////////////////////////////////
 
+// forceAll, when set, causes every job to run regardless of what
+// c.Changed() reports. It's set from the --force flag and by the
+// benchmark subcommand, which forces it unconditionally so that
+// per-iteration timings are comparable across runs.
+var forceAll bool
+
+// jobsTotal, jobsRun, and jobsCached count, respectively, how many jobs
+// were registered, how many actually executed, and how many were
+// short-circuited by ordinary (non-forced) change detection during the
+// last build. Jobs run concurrently in modulir's worker pool, so all
+// three are manipulated with atomic operations. They're reset and read
+// by the benchmark subcommand to compute a cache hit ratio.
+//
+// jobsCached exists separately from jobsRun because forceAll (used by
+// both --force and the benchmark subcommand) makes every job run
+// regardless of whether it would otherwise have been skipped — without
+// it, "cache hit ratio" would be meaningless under forcing, since
+// jobsRun would always equal jobsTotal.
+var (
+	jobsTotal  int64
+	jobsRun    int64
+	jobsCached int64
+)
+
+// instrumentedAddJob registers a job with c the same way c.AddJob does,
+// but also times it and, when checkRecorder is set (i.e. the check
+// subcommand is running), records its name/source/target/duration.
+// Every job build() registers should go through this rather than
+// calling c.AddJob directly, so that check continues to see every job
+// in the graph without each call site having to hand-roll its own
+// bookkeeping.
+func instrumentedAddJob(c *modulir.Context, name, source, target string, fn func() (bool, error)) {
+	atomic.AddInt64(&jobsTotal, 1)
+
+	c.AddJob(name, func() (bool, error) {
+		start := time.Now()
+		ran, err := fn()
+
+		if checkRecorder != nil {
+			checkRecorder.record(checkJobRecord{
+				Name:     name,
+				Source:   source,
+				Target:   target,
+				Duration: time.Since(start),
+			})
+		}
+
+		return ran, err
+	})
+}
+
 func build(c *modulir.Context) []error {
 	//
 	// Phase 0: Setup
@@ -34,7 +87,7 @@ func build(c *modulir.Context) []error {
 			c.TargetDir + "/hello",
 		}
 		for _, dir := range commonDirs {
-			err := mfile.EnsureDir(c, dir)
+			err := ensureDir(c, dir)
 			if err != nil {
 				return []error{err}
 			}
@@ -42,16 +95,21 @@ func build(c *modulir.Context) []error {
 	}
 
 	{
-		c.AddJob("hello", func() (bool, error) {
-			source := c.SourceDir + "/content/hello.html"
-			target := c.TargetDir + "/hello/index.html"
+		source := c.SourceDir + "/content/hello.html"
+		target := c.TargetDir + "/hello/index.html"
 
+		instrumentedAddJob(c, "hello", source, target, func() (bool, error) {
 			sourceChanged := c.Changed(source)
-			if !sourceChanged && !c.Forced() {
-				return false, nil
+			cached := !sourceChanged && !c.Forced()
+			if cached {
+				atomic.AddInt64(&jobsCached, 1)
+				if !forceAll {
+					return false, nil
+				}
 			}
 
-			return true, mfile.CopyFile(c, source, target)
+			atomic.AddInt64(&jobsRun, 1)
+			return true, copyFile(c, source, target)
 		})
 	}
 
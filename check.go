@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brandur/modulir"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+//////////////////////////////////////////////////////////////////////////////
+//
+//
+//
+// Check command
+//
+//
+//
+//////////////////////////////////////////////////////////////////////////////
+
+// checkJobRecord describes one execution of a job during a build run
+// under the check subcommand.
+type checkJobRecord struct {
+	Name     string
+	Source   string
+	Target   string
+	Duration time.Duration
+}
+
+// checkResults accumulates checkJobRecords across a build. Jobs run
+// concurrently in modulir's worker pool, so access is guarded by a
+// mutex.
+type checkResults struct {
+	mu   sync.Mutex
+	jobs []checkJobRecord
+}
+
+func (r *checkResults) record(rec checkJobRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, rec)
+}
+
+// checkRecorder, when non-nil, is populated by build() as jobs run so
+// that the check subcommand can analyze the result afterward. It's nil
+// during normal build/loop/benchmark runs.
+var checkRecorder *checkResults
+
+// checkOptions holds the flags accepted by the `check` subcommand.
+type checkOptions struct {
+	jobBudget time.Duration
+}
+
+func newCheckCmd(flags *buildFlags) *cobra.Command {
+	opts := &checkOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Validate the build graph without writing any output",
+		Long: strings.TrimSpace(`
+Runs the build function against an in-memory target, inspired by Hugo's
+check command, and reports on a handful of problem classes: jobs that
+declare no input (and so always rebuild), multiple jobs that write the
+same target path (a race hazard), source files under SourceDir/content
+that no job reads (other files under SourceDir are not checked), broken relative links in the produced HTML, and jobs that
+exceed --job-budget. Exits non-zero if any class of problem is found,
+making it suitable to run in CI.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCheck(flags, opts)
+		},
+	}
+
+	cmd.Flags().DurationVar(&opts.jobBudget, "job-budget", time.Second,
+		"Per-job time budget; jobs slower than this are reported")
+
+	return cmd
+}
+
+// runCheck runs build() with output routed to an in-memory filesystem
+// (so nothing is ever written to disk) and reports on the problems
+// described in newCheckCmd's help text. It returns an error, and so
+// exits non-zero, if any problem is found.
+func runCheck(flags *buildFlags, opts *checkOptions) error {
+	checkRecorder = &checkResults{}
+	defer func() { checkRecorder = nil }()
+
+	forceAll = true
+	defer func() { forceAll = false }()
+
+	enableMemFs()
+
+	config, err := flags.modulirConfig()
+	if err != nil {
+		return err
+	}
+
+	if errs := modulir.Build(config, build); len(errs) > 0 {
+		return fmt.Errorf("build produced %d error(s): %v", len(errs), errs)
+	}
+
+	var problems int
+
+	problems += reportNoInputJobs(checkRecorder.jobs)
+	problems += reportDuplicateTargets(checkRecorder.jobs)
+	problems += reportUnreachableSources(config.SourceDir, checkRecorder.jobs)
+	problems += reportBrokenLinks(config.TargetDir)
+	problems += reportSlowJobs(checkRecorder.jobs, opts.jobBudget)
+
+	if problems > 0 {
+		return fmt.Errorf("check found %d problem(s)", problems)
+	}
+
+	fmt.Println("check: no problems found")
+	return nil
+}
+
+// reportNoInputJobs prints and counts jobs that declared no source
+// file, meaning they have no way to participate in change detection and
+// will always rebuild.
+func reportNoInputJobs(jobs []checkJobRecord) int {
+	var n int
+	for _, j := range jobs {
+		if j.Source == "" {
+			fmt.Printf("always-rebuild: job %q declares no input\n", j.Name)
+			n++
+		}
+	}
+	return n
+}
+
+// reportDuplicateTargets prints and counts cases where more than one job
+// writes the same target path, which is a race hazard under modulir's
+// concurrent job pool.
+func reportDuplicateTargets(jobs []checkJobRecord) int {
+	byTarget := make(map[string][]string)
+	for _, j := range jobs {
+		byTarget[j.Target] = append(byTarget[j.Target], j.Name)
+	}
+
+	var n int
+	for target, names := range byTarget {
+		if len(names) > 1 {
+			fmt.Printf("race hazard: target %q is written by jobs %v\n", target, names)
+			n++
+		}
+	}
+	return n
+}
+
+// reportUnreachableSources prints and counts files under sourceDir's
+// content directory that no recorded job read, meaning the build will
+// never touch them. Files elsewhere under sourceDir are not walked.
+func reportUnreachableSources(sourceDir string, jobs []checkJobRecord) int {
+	touched := make(map[string]bool)
+	for _, j := range jobs {
+		if j.Source != "" {
+			touched[filepath.Clean(j.Source)] = true
+		}
+	}
+
+	var n int
+	contentDir := filepath.Join(sourceDir, "content")
+	_ = filepath.Walk(contentDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info == nil || info.IsDir() {
+			return nil
+		}
+		if !touched[filepath.Clean(p)] {
+			fmt.Printf("unreachable: source file %q isn't read by any job\n", p)
+			n++
+		}
+		return nil
+	})
+	return n
+}
+
+// linkPattern extracts the value of href and src attributes from HTML.
+var linkPattern = regexp.MustCompile(`(?:href|src)="([^"]+)"`)
+
+// reportBrokenLinks walks the in-memory target tree looking for HTML
+// files, extracts their relative links, and reports any that don't
+// resolve to another file in the tree.
+func reportBrokenLinks(targetDir string) int {
+	var n int
+
+	_ = afero.Walk(memFs, targetDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info == nil || info.IsDir() || !strings.HasSuffix(p, ".html") {
+			return nil
+		}
+
+		data, err := afero.ReadFile(memFs, p)
+		if err != nil {
+			return nil
+		}
+
+		for _, match := range linkPattern.FindAllStringSubmatch(string(data), -1) {
+			link := match[1]
+			if isExternalLink(link) {
+				continue
+			}
+
+			path, _, _ := strings.Cut(link, "#")
+			path, _, _ = strings.Cut(path, "?")
+
+			var resolved string
+			if strings.HasPrefix(path, "/") {
+				resolved = filepath.Join(targetDir, path)
+			} else {
+				resolved = filepath.Join(filepath.Dir(p), path)
+			}
+
+			if exists, _ := afero.Exists(memFs, resolved); !exists {
+				fmt.Printf("broken link: %s references %q, which doesn't exist\n", p, link)
+				n++
+			}
+		}
+
+		return nil
+	})
+
+	return n
+}
+
+// isExternalLink reports whether link points outside of the local site
+// (an absolute URL, a protocol-relative URL, an anchor, or a mailto:
+// link) and so shouldn't be checked against the local tree.
+func isExternalLink(link string) bool {
+	if link == "" || strings.HasPrefix(link, "#") {
+		return true
+	}
+	if strings.HasPrefix(link, "//") {
+		return true
+	}
+	if u, err := url.Parse(link); err == nil && u.Scheme != "" {
+		return true
+	}
+	return false
+}
+
+// reportSlowJobs prints and counts jobs whose recorded duration exceeded
+// budget.
+func reportSlowJobs(jobs []checkJobRecord, budget time.Duration) int {
+	var n int
+	for _, j := range jobs {
+		if j.Duration > budget {
+			fmt.Printf("slow job: %q took %v, exceeding the %v budget\n", j.Name, j.Duration, budget)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestReportDuplicateTargets(t *testing.T) {
+	cases := []struct {
+		name string
+		jobs []checkJobRecord
+		want int
+	}{
+		{
+			name: "no duplicates",
+			jobs: []checkJobRecord{
+				{Name: "a", Target: "public/a.html"},
+				{Name: "b", Target: "public/b.html"},
+			},
+			want: 0,
+		},
+		{
+			name: "one duplicate target",
+			jobs: []checkJobRecord{
+				{Name: "a", Target: "public/index.html"},
+				{Name: "b", Target: "public/index.html"},
+			},
+			want: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := reportDuplicateTargets(c.jobs); got != c.want {
+				t.Errorf("reportDuplicateTargets() = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestIsExternalLink(t *testing.T) {
+	cases := []struct {
+		link string
+		want bool
+	}{
+		{"", true},
+		{"#section", true},
+		{"//cdn.example.com/lib.js", true},
+		{"https://example.com", true},
+		{"mailto:hello@example.com", true},
+		{"style.css", false},
+		{"style.css?v=2", false},
+		{"/assets/style.css", false},
+		{"../style.css#top", false},
+	}
+
+	for _, c := range cases {
+		if got := isExternalLink(c.link); got != c.want {
+			t.Errorf("isExternalLink(%q) = %v, want %v", c.link, got, c.want)
+		}
+	}
+}
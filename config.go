@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+//////////////////////////////////////////////////////////////////////////////
+//
+//
+//
+// Config file loading
+//
+//
+//
+//////////////////////////////////////////////////////////////////////////////
+
+// cfgFile holds the value of the top-level --config flag. When set, it
+// names an explicit config file to load; otherwise initConfig searches
+// the usual locations for one.
+var cfgFile string
+
+// installConfigFlag registers the --config persistent flag on cmd.
+func installConfigFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&cfgFile, "config", "",
+		"Config file (default searches the working directory and $HOME for .modulir.{yaml,toml,json})")
+}
+
+// initConfig loads a modulir config file via viper, merging it with any
+// MODULIR_* environment variables. It's run by cobra before every
+// command via cobra.OnInitialize, after flags have been parsed but
+// before a command's Run executes, so precedence among the values
+// buildFlags exposes ends up being: flag > env var > config file >
+// default.
+func initConfig() {
+	if cfgFile != "" {
+		viper.SetConfigFile(cfgFile)
+	} else {
+		home, err := os.UserHomeDir()
+		cobra.CheckErr(err)
+
+		viper.AddConfigPath(".")
+		viper.AddConfigPath(home)
+		viper.SetConfigName(".modulir")
+	}
+
+	viper.SetEnvPrefix("MODULIR")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
+	if err := viper.ReadInConfig(); err == nil {
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////
+//
+//
+//
+// Config command
+//
+//
+//
+//////////////////////////////////////////////////////////////////////////////
+
+// newConfigCmd builds the `config` command group, currently just
+// `config print`, which is useful for debugging flag/env/file
+// precedence.
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the effective modulir configuration",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "print",
+		Short: "Print the effective merged configuration as YAML",
+		Long: strings.TrimSpace(`
+Dumps the configuration that results from merging config file, MODULIR_*
+environment variable, and flag values, in that order of increasing
+precedence. Useful for debugging why a setting isn't taking effect.`),
+		Run: func(cmd *cobra.Command, args []string) {
+			out, err := yaml.Marshal(viper.AllSettings())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error marshaling config: %v", err)
+				os.Exit(1)
+			}
+			fmt.Print(string(out))
+		},
+	})
+
+	return cmd
+}
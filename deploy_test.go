@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestTransformDeployFile(t *testing.T) {
+	matchers := []deployMatcher{
+		{Pattern: "*.html", CacheControl: "no-cache"},
+		{Pattern: "*.css", CacheControl: "max-age=31536000", Gzip: true},
+		{Pattern: "*", CacheControl: "max-age=60"},
+	}
+
+	t.Run("first matching pattern wins", func(t *testing.T) {
+		data, opts, err := transformDeployFile("index.html", []byte("<html></html>"), matchers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "<html></html>" {
+			t.Errorf("data was transformed unexpectedly: %q", data)
+		}
+		if opts.CacheControl != "no-cache" {
+			t.Errorf("CacheControl = %q, want %q", opts.CacheControl, "no-cache")
+		}
+	})
+
+	t.Run("gzip compresses data and sets ContentEncoding", func(t *testing.T) {
+		original := []byte("body { color: red; }")
+
+		data, opts, err := transformDeployFile("style.css", original, matchers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.ContentEncoding != "gzip" {
+			t.Errorf("ContentEncoding = %q, want %q", opts.ContentEncoding, "gzip")
+		}
+		if bytes.Equal(data, original) {
+			t.Errorf("data wasn't compressed: %q", data)
+		}
+
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("compressed data doesn't decompress: %v", err)
+		}
+		decompressed, err := io.ReadAll(gr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decompressed, original) {
+			t.Errorf("decompressed = %q, want %q", decompressed, original)
+		}
+	})
+
+	t.Run("falls through to a later pattern when earlier ones don't match", func(t *testing.T) {
+		data, opts, err := transformDeployFile("favicon.ico", []byte("binary"), matchers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "binary" {
+			t.Errorf("data was transformed unexpectedly: %q", data)
+		}
+		if opts.CacheControl != "max-age=60" {
+			t.Errorf("CacheControl = %q, want %q", opts.CacheControl, "max-age=60")
+		}
+	})
+
+	t.Run("no pattern matches leaves data and options untouched", func(t *testing.T) {
+		data, opts, err := transformDeployFile("README", []byte("hello"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "hello" {
+			t.Errorf("data was transformed unexpectedly: %q", data)
+		}
+		if opts.CacheControl != "" || opts.ContentEncoding != "" {
+			t.Errorf("expected zero-value options, got %+v", opts)
+		}
+	})
+}
+
+// TestTransformDeployFileStableHash guards against the regression fixed
+// alongside the deploy flag-collision bug: localDeployFiles must hash the
+// same (possibly gzip-transformed) bytes that uploadDeployFile actually
+// transmits, or change detection skips re-uploading files that changed.
+func TestTransformDeployFileStableHash(t *testing.T) {
+	matchers := []deployMatcher{
+		{Pattern: "*.css", Gzip: true},
+	}
+
+	data1, _, err := transformDeployFile("style.css", []byte("body {}"), matchers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, _, err := transformDeployFile("style.css", []byte("body {}"), matchers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data1, data2) {
+		t.Errorf("transformDeployFile isn't stable across calls with identical input")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/brandur/modulir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+//////////////////////////////////////////////////////////////////////////////
+//
+//
+//
+// buildFlags
+//
+//
+//
+//////////////////////////////////////////////////////////////////////////////
+
+// buildFlags holds the set of flags common to every subcommand that
+// drives a Modulir build (`build`, `loop`, `benchmark`, and so on). It's
+// installed once as a set of persistent flags on rootCmd so that every
+// subcommand consumes the same values rather than hard-coding its own.
+//
+// Each flag is also bound to viper (see initConfig), so the effective
+// value at read time (via the accessor methods below) comes from, in
+// order of precedence: an explicit flag > environment variable
+// (MODULIR_*) > config file value > default.
+type buildFlags struct {
+	source         string
+	target         string
+	port           int
+	concurrency    int
+	verbose        bool
+	quiet          bool
+	logFile        string
+	force          bool
+	renderToMemory bool
+	websocket      bool
+}
+
+// install registers the struct's fields as persistent flags on cmd and
+// binds each one to viper.
+func (f *buildFlags) install(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+
+	flags.StringVar(&f.source, "source", ".", "Directory containing source content")
+	flags.StringVar(&f.target, "target", "./public", "Directory to place build output")
+	flags.IntVar(&f.port, "port", 5004, "Port to serve the built site on")
+	flags.IntVar(&f.concurrency, "concurrency", 30, "Number of concurrent jobs to run during the build")
+	flags.BoolVarP(&f.verbose, "verbose", "v", false, "Enable verbose (debug) logging")
+	flags.BoolVarP(&f.quiet, "quiet", "q", false, "Suppress all but error logging")
+	flags.StringVar(&f.logFile, "log-file", "", "Write log output to this file instead of stderr")
+	flags.BoolVarP(&f.force, "force", "f", false, "Force a full rebuild, ignoring change detection")
+	flags.BoolVar(&f.renderToMemory, "render-to-memory", false, "Render build output to an in-memory filesystem instead of to target")
+	flags.BoolVar(&f.websocket, "websocket", true, "Run a websocket for live reload notifications")
+
+	for _, name := range []string{
+		"source", "target", "port", "concurrency", "verbose", "quiet",
+		"log-file", "force", "render-to-memory", "websocket",
+	} {
+		_ = viper.BindPFlag(name, flags.Lookup(name))
+	}
+
+	// The config file schema documented for chunk0-3 spells these two
+	// keys "source_dir"/"target_dir" rather than after the flag names;
+	// register them as aliases so a config file written that way is
+	// actually honored instead of silently ignored.
+	viper.RegisterAlias("source_dir", "source")
+	viper.RegisterAlias("target_dir", "target")
+}
+
+// Source, Target, Port, Concurrency, Verbose, Quiet, LogFile, Force,
+// RenderToMemory, and Websocket read the effective value of their
+// namesake flag back out of viper, after config file and environment
+// variable merging (see initConfig).
+func (f *buildFlags) Source() string       { return viper.GetString("source") }
+func (f *buildFlags) Target() string       { return viper.GetString("target") }
+func (f *buildFlags) Port() int            { return viper.GetInt("port") }
+func (f *buildFlags) Concurrency() int     { return viper.GetInt("concurrency") }
+func (f *buildFlags) Verbose() bool        { return viper.GetBool("verbose") }
+func (f *buildFlags) Quiet() bool          { return viper.GetBool("quiet") }
+func (f *buildFlags) LogFile() string      { return viper.GetString("log-file") }
+func (f *buildFlags) Force() bool          { return viper.GetBool("force") }
+func (f *buildFlags) RenderToMemory() bool { return viper.GetBool("render-to-memory") }
+func (f *buildFlags) Websocket() bool      { return viper.GetBool("websocket") }
+
+// modulirConfig interprets the flags to produce a configuration suitable
+// to pass to a Modulir build loop.
+func (f *buildFlags) modulirConfig() (*modulir.Config, error) {
+	level := modulir.LevelInfo
+	switch {
+	case f.Verbose():
+		level = modulir.LevelDebug
+	case f.Quiet():
+		level = modulir.LevelError
+	}
+
+	var logWriter io.Writer = os.Stderr
+	if logFile := f.LogFile(); logFile != "" {
+		file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		logWriter = file
+	}
+
+	return &modulir.Config{
+		Concurrency: f.Concurrency(),
+		Log:         &modulir.Logger{Level: level, Writer: logWriter},
+		Port:        f.Port(),
+		SourceDir:   f.Source(),
+		TargetDir:   f.Target(),
+		Websocket:   f.Websocket(),
+	}, nil
+}
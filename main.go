@@ -20,6 +20,10 @@ import (
 //////////////////////////////////////////////////////////////////////////////
 
 func main() {
+	cobra.OnInitialize(initConfig)
+
+	flags := &buildFlags{}
+
 	var rootCmd = &cobra.Command{
 		Use:   "modulir-sample",
 		Short: "Sample program demonstrating Modulir",
@@ -28,6 +32,9 @@ Sorg is a static site generator for Brandur's personal
 homepage and some of its adjacent functions. See the product
 in action at https://brandur.org.`),
 	}
+	installConfigFlag(rootCmd)
+	flags.install(rootCmd)
+
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "build",
 		Short: "Run a single build loop",
@@ -36,7 +43,16 @@ Starts the build loop that watches for local changes and runs
 when they're detected. A webserver is started on PORT (default
 5004).`),
 		Run: func(cmd *cobra.Command, args []string) {
-			modulir.Build(getModulirConfig(), build)
+			config, err := flags.modulirConfig()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error building config: %v", err)
+				os.Exit(1)
+			}
+			forceAll = flags.Force()
+			if flags.RenderToMemory() {
+				enableMemFs()
+			}
+			modulir.Build(config, build)
 		},
 	})
 	rootCmd.AddCommand(&cobra.Command{
@@ -45,35 +61,31 @@ when they're detected. A webserver is started on PORT (default
 		Long: strings.TrimSpace(`
 Runs the build loop one time and places the result in ./public.`),
 		Run: func(cmd *cobra.Command, args []string) {
-			modulir.BuildLoop(getModulirConfig(), build)
+			config, err := flags.modulirConfig()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error building config: %v", err)
+				os.Exit(1)
+			}
+			forceAll = flags.Force()
+			if flags.RenderToMemory() {
+				if flags.Websocket() {
+					fmt.Fprintf(os.Stderr, "Warning: --websocket has no effect with --render-to-memory (runMemoryLoop doesn't run a websocket server); disabling live reload\n")
+					config.Websocket = false
+				}
+				enableMemFs()
+				runMemoryLoop(config)
+				return
+			}
+			modulir.BuildLoop(config, build)
 		},
 	})
+	rootCmd.AddCommand(newBenchmarkCmd(flags))
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newDeployCmd(flags))
+	rootCmd.AddCommand(newCheckCmd(flags))
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error executing command: %v", err)
 		os.Exit(1)
 	}
 }
-
-//////////////////////////////////////////////////////////////////////////////
-//
-//
-//
-// Private
-//
-//
-//
-//////////////////////////////////////////////////////////////////////////////
-
-// getModulirConfig interprets Conf to produce a configuration suitable to pass
-// to a Modulir build loop.
-func getModulirConfig() *modulir.Config {
-	return &modulir.Config{
-		Concurrency:    30,
-		Log:            &modulir.Logger{Level: modulir.LevelInfo},
-		Port:           5004,
-		SourceDir:      ".",
-		TargetDir:      "./public",
-		Websocket:      true,
-	}
-}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/brandur/modulir"
+	"github.com/brandur/modulir/modules/mfile"
+	"github.com/spf13/afero"
+)
+
+//////////////////////////////////////////////////////////////////////////////
+//
+//
+//
+// In-memory rendering
+//
+//
+//
+//////////////////////////////////////////////////////////////////////////////
+
+// memFs is the in-memory filesystem the build renders to when
+// --render-to-memory is set. It's nil in the default, disk-backed mode,
+// in which case ensureDir and copyFile fall back to mfile's real,
+// disk-backed operations.
+var memFs afero.Fs
+
+// enableMemFs switches the build over to rendering into an in-memory
+// filesystem instead of writing to TargetDir on disk.
+func enableMemFs() {
+	memFs = afero.NewMemMapFs()
+}
+
+// ensureDir creates dir, either on disk via mfile or, when
+// --render-to-memory is set, in memFs.
+func ensureDir(c *modulir.Context, dir string) error {
+	if memFs != nil {
+		return memFs.MkdirAll(dir, 0755)
+	}
+	return mfile.EnsureDir(c, dir)
+}
+
+// copyFile copies source (always read from disk — only build output,
+// not input content, moves into memory) to target, either on disk via
+// mfile or, when --render-to-memory is set, into memFs.
+func copyFile(c *modulir.Context, source, target string) error {
+	if memFs != nil {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		if err := memFs.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return afero.WriteFile(memFs, target, data, 0644)
+	}
+	return mfile.CopyFile(c, source, target)
+}
+
+// runMemoryLoop stands in for modulir.BuildLoop when rendering to
+// memory. modulir's own built-in webserver only knows how to serve a
+// disk directory, so this instead rebuilds on a short interval and
+// serves memFs itself.
+func runMemoryLoop(config *modulir.Config) {
+	if errs := modulir.Build(config, build); len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "Error in initial build: %v", errs)
+		os.Exit(1)
+	}
+
+	go func() {
+		for range time.Tick(500 * time.Millisecond) {
+			modulir.Build(config, build)
+		}
+	}()
+
+	addr := fmt.Sprintf(":%d", config.Port)
+	fmt.Printf("Serving in-memory build from %s on %s\n", config.TargetDir, addr)
+
+	handler := http.FileServer(afero.NewHttpFs(memFs).Dir(config.TargetDir))
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		fmt.Fprintf(os.Stderr, "Error serving: %v", err)
+		os.Exit(1)
+	}
+}